@@ -0,0 +1,26 @@
+package tiktokscraper
+
+import "io"
+
+// BlobStore abstracts the object storage tiktok-scraper caches metadata,
+// videos, and images into. Keys are logical paths such as "img/<hash>" or
+// "<username>/<videoid>.json" — implementations are responsible for their
+// own prefixing and layout.
+type BlobStore interface {
+	// Has reports whether key already exists in the store.
+	Has(key string) bool
+
+	// Get returns the contents of key. The caller must close the returned
+	// reader.
+	Get(key string) (io.ReadCloser, error)
+
+	// Put uploads body to key with the given content type.
+	Put(key string, body io.Reader, contentType string) error
+
+	// Delete removes key. Deleting a key that doesn't exist is not an
+	// error.
+	Delete(key string) error
+
+	// PublicURL returns the URL clients should use to fetch key.
+	PublicURL(key string) string
+}
@@ -0,0 +1,262 @@
+package tiktokscraper
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cachedMetaRecord is the envelope metadata is actually stored as, so
+// cachedMetadata can tell how old an entry is (for CacheTTL) and whether it
+// represents a negatively-cached "not found" result (for NegativeCacheTTL).
+type cachedMetaRecord struct {
+	CachedAt time.Time   `json:"cachedAt"`
+	NotFound bool        `json:"notFound,omitempty"`
+	Meta     *TikTokMeta `json:"meta,omitempty"`
+}
+
+func (tts *TikTokScraper) cached(key string) bool {
+	return tts.store.Has(key)
+}
+
+// cachedMetadata returns cached metadata for key. negative reports a cached
+// "not found" result within NegativeCacheTTL. stale reports that meta is
+// past CacheTTL but is being returned anyway because StaleWhileRevalidate is
+// set — the caller is expected to kick off a background refresh.
+func (tts *TikTokScraper) cachedMetadata(key string) (meta *TikTokMeta, negative bool, stale bool, err error) {
+	if !tts.cached(key + ".json") {
+		return nil, false, false, nil
+	}
+
+	body, err := tts.store.Get(key + ".json")
+	if err != nil {
+		return nil, false, false, err
+	}
+	defer body.Close()
+
+	bs, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	rec := &cachedMetaRecord{}
+	if err = json.Unmarshal(bs, rec); err != nil {
+		return nil, false, false, err
+	}
+
+	if rec.NotFound {
+		if tts.c.NegativeCacheTTL > 0 && time.Since(rec.CachedAt) > tts.c.NegativeCacheTTL {
+			return nil, false, false, nil
+		}
+		return nil, true, false, nil
+	}
+
+	if tts.c.CacheTTL == 0 || time.Since(rec.CachedAt) <= tts.c.CacheTTL {
+		return rec.Meta, false, false, nil
+	}
+
+	if tts.c.StaleWhileRevalidate {
+		return rec.Meta, false, true, nil
+	}
+
+	return nil, false, false, nil
+}
+
+func (tts *TikTokScraper) cacheMetadata(key string, ttm *TikTokMeta) error {
+	return tts.putMetaRecord(key, &cachedMetaRecord{CachedAt: time.Now(), Meta: ttm})
+}
+
+// cacheNotFound negatively caches a video that TikTok reports as gone, so
+// repeat requests don't keep round-tripping to TikTok just to get another
+// 404.
+func (tts *TikTokScraper) cacheNotFound(key string) error {
+	return tts.putMetaRecord(key, &cachedMetaRecord{CachedAt: time.Now(), NotFound: true})
+}
+
+func (tts *TikTokScraper) putMetaRecord(key string, rec *cachedMetaRecord) error {
+	jsbs, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return tts.store.Put(key+".json", bytes.NewReader(jsbs), "application/json")
+}
+
+// PurgeVideo removes a video's cached metadata, video/audio, and image
+// objects, for DMCA/takedown handling. It's safe to call even if nothing is
+// cached for username/videoID.
+func (tts *TikTokScraper) PurgeVideo(username, videoID string) error {
+	if !validCacheIdentifier(username) || !validCacheIdentifier(videoID) {
+		return errors.New("invalid username or videoID")
+	}
+
+	cacheKey := username + "/" + videoID
+
+	tts.lock.Lock(cacheKey)
+	defer tts.lock.Unlock(cacheKey)
+
+	rec, err := tts.rawMetaRecord(cacheKey)
+	if err != nil {
+		return err
+	}
+
+	if rec != nil && rec.Meta != nil {
+		for _, key := range tts.imageKeysFromMeta(rec.Meta) {
+			if err := tts.store.Delete(key); err != nil {
+				log.WithError(err).Warn("failed to delete cached image ", key)
+			}
+		}
+
+		if rec.Meta.Video.Format != "" {
+			if err := tts.store.Delete(cacheKey + "." + rec.Meta.Video.Format); err != nil {
+				log.WithError(err).Warn("failed to delete cached video for ", cacheKey)
+			}
+		}
+
+		if rec.Meta.Audio != "" {
+			if err := tts.store.Delete("audio/" + cacheKey + ".mp3"); err != nil {
+				log.WithError(err).Warn("failed to delete cached audio for ", cacheKey)
+			}
+		}
+	}
+
+	return tts.store.Delete(cacheKey + ".json")
+}
+
+// rawMetaRecord reads back a metadata record ignoring CacheTTL/
+// NegativeCacheTTL, since callers like PurgeVideo need the underlying data
+// regardless of freshness. Returns nil, nil if nothing is cached.
+func (tts *TikTokScraper) rawMetaRecord(key string) (*cachedMetaRecord, error) {
+	if !tts.cached(key + ".json") {
+		return nil, nil
+	}
+
+	body, err := tts.store.Get(key + ".json")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	bs, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &cachedMetaRecord{}
+	if err := json.Unmarshal(bs, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// imageKeysFromMeta recovers the BlobStore keys backing a cached
+// TikTokMeta's images, by stripping the store's public URL prefix back off.
+func (tts *TikTokScraper) imageKeysFromMeta(ttm *TikTokMeta) []string {
+	prefix := tts.store.PublicURL("")
+
+	urls := []string{
+		ttm.Video.Cover,
+		ttm.Video.OriginCover,
+		ttm.Video.DynamicCover,
+		ttm.Author.AvatarLarger,
+		ttm.Author.AvatarMedium,
+		ttm.Author.AvatarThumb,
+	}
+	urls = append(urls, ttm.Images...)
+
+	keys := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u == "" || !strings.HasPrefix(u, prefix) {
+			continue
+		}
+		keys = append(keys, strings.TrimPrefix(u, prefix))
+	}
+	return keys
+}
+
+func (tts *TikTokScraper) cacheVideo(key string, body io.ReadCloser, ctype string) error {
+	return tts.store.Put(key, body, ctype)
+}
+
+func (tts *TikTokScraper) processImages(ttm *TikTokMeta) error {
+	cover, err := tts.cacheImage(ttm.Video.Cover)
+	if err != nil {
+		return err
+	}
+	ttm.Video.Cover = cover
+
+	originCover, err := tts.cacheImage(ttm.Video.OriginCover)
+	if err != nil {
+		return err
+	}
+	ttm.Video.OriginCover = originCover
+
+	dynamicCover, err := tts.cacheImage(ttm.Video.DynamicCover)
+	if err != nil {
+		return err
+	}
+	ttm.Video.DynamicCover = dynamicCover
+
+	avatarLarge, err := tts.cacheImage(ttm.Author.AvatarLarger)
+	if err != nil {
+		return err
+	}
+	ttm.Author.AvatarLarger = avatarLarge
+
+	avatarMedium, err := tts.cacheImage(ttm.Author.AvatarMedium)
+	if err != nil {
+		return err
+	}
+	ttm.Author.AvatarMedium = avatarMedium
+
+	avatarThumb, err := tts.cacheImage(ttm.Author.AvatarThumb)
+	if err != nil {
+		return err
+	}
+	ttm.Author.AvatarThumb = avatarThumb
+
+	return nil
+}
+
+func (tts *TikTokScraper) cacheImage(url string) (string, error) {
+	// some items (e.g. slideshow/photo-mode posts) don't have a real
+	// underlying video, so their Video sub-struct's cover fields are blank;
+	// skip those instead of attempting a request to an empty URL
+	if url == "" {
+		return "", nil
+	}
+
+	sum := md5.Sum([]byte(url))
+	hash := hex.EncodeToString(sum[:])
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range tts.cookies {
+		req.AddCookie(&http.Cookie{Name: k, Value: v})
+	}
+
+	resp, err := tts.doProxiedRequest(req, hash)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	key := "img/" + hash
+
+	if err := tts.store.Put(key, resp.Body, resp.Header.Get("Content-Type")); err != nil {
+		return "", err
+	}
+
+	return tts.store.PublicURL(key), nil
+}
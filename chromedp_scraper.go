@@ -0,0 +1,69 @@
+package tiktokscraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/threadproc/tiktok-scraper/resources"
+)
+
+// scrapeVideoChromedp is the last-resort fallback scraper: it renders
+// @user/video/id in a real headless Chromium via chromedp and evaluates
+// resources.UniversalDataScript to pull the hydration JSON back out, for
+// when node/share/video and the plain page scrape are both gated behind a
+// captcha. It's only used when TikTokScraperConfig.ChromedpEnabled is set,
+// since spinning up a browser per request is far more expensive than the
+// HTTP-only tiers.
+func (tts *TikTokScraper) scrapeVideoChromedp(username, videoID string) (*TikTokMeta, error) {
+	ttURL := fmt.Sprintf("https://www.tiktok.com/@%s/video/%s", username, videoID)
+
+	// route the headless browser through the same proxy pool as the HTTP
+	// tiers, pinned to this cacheKey, so the last-resort fallback doesn't
+	// hit TikTok from the origin IP and undo the ban-avoidance the cheaper
+	// tiers already get
+	proxyURL := tts.proxies.Pick(username + "/" + videoID)
+
+	allocOpts := chromedp.DefaultExecAllocatorOptions[:]
+	if tts.c.ChromedpExecPath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(tts.c.ChromedpExecPath))
+	}
+	if proxyURL != "" {
+		allocOpts = append(allocOpts, chromedp.ProxyServer(proxyURL))
+	}
+
+	actx, acancel := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	defer acancel()
+
+	ctx, cancel := chromedp.NewContext(actx)
+	defer cancel()
+
+	ctx, timeoutCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer timeoutCancel()
+
+	var raw string
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(ttURL),
+		chromedp.Sleep(3*time.Second),
+		chromedp.Evaluate(resources.UniversalDataScript, &raw),
+	); err != nil {
+		if proxyURL != "" {
+			tts.proxies.MarkFailure(proxyURL)
+		}
+		return nil, err
+	}
+
+	if raw == "" {
+		return nil, nil
+	}
+
+	data := &universalData{}
+	if err := json.Unmarshal([]byte(raw), data); err != nil {
+		return nil, err
+	}
+
+	return data.DefaultScope.WebappVideoDetail.ItemInfo.ItemStruct, nil
+}
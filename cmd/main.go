@@ -4,14 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+
 	tiktokscraper "github.com/threadproc/tiktok-scraper"
+	"github.com/threadproc/tiktok-scraper/metrics"
 )
 
 var tts *tiktokscraper.TikTokScraper
+var cfg *tiktokscraper.TikTokScraperConfig
 
 type ttResponse struct {
 	Error string                    `json:"error,omitempty"`
@@ -45,6 +51,39 @@ func handleVideoRequest(c *gin.Context) {
 	})
 }
 
+func handleProxyStatus(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"proxies": tts.ProxyStatus(),
+	})
+}
+
+func handleDeleteVideo(c *gin.Context) {
+	if cfg.DeleteAuthToken == "" || c.GetHeader("X-Auth-Token") != cfg.DeleteAuthToken {
+		errResponse(c, http.StatusUnauthorized, errors.New("invalid or missing auth token"))
+		return
+	}
+
+	username := c.Param("username")
+	videoID := c.Param("videoid")
+
+	if err := tts.PurgeVideo(username, videoID); err != nil {
+		errResponse(c, 500, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// metricsMiddleware records per-route latency and response status, so the
+// HTTP surface has the same observability as the scraper internals.
+func metricsMiddleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	metrics.HTTPRequestDuration.WithLabelValues(c.FullPath(), c.Request.Method, strconv.Itoa(c.Writer.Status())).
+		Observe(time.Since(start).Seconds())
+}
+
 func handleShortURL(c *gin.Context) {
 	username, videoID, err := tts.ResolveHash(c.Param("hash"))
 	if err != nil {
@@ -65,21 +104,29 @@ func handleShortURL(c *gin.Context) {
 func main() {
 	log.Info("🚀 Starting tiktok-scraper-lambda")
 
-	c := &tiktokscraper.TikTokScraperConfig{}
-	if _, err := toml.DecodeFile("config.toml", c); err != nil {
+	cfg = &tiktokscraper.TikTokScraperConfig{}
+	if _, err := toml.DecodeFile("config.toml", cfg); err != nil {
 		log.WithError(err).Fatal("could not load config file")
 	}
 
 	var err error
-	tts, err = tiktokscraper.NewScraper(c)
+	tts, err = tiktokscraper.NewScraper(cfg)
 	if err != nil {
 		log.WithError(err).Fatal("failed to initialize tiktok scraper")
 	}
 
 	r := gin.Default()
+	r.Use(metricsMiddleware)
 
 	r.GET("/hash/:hash", handleShortURL)
 	r.GET("/video/:username/:videoid", handleVideoRequest)
+	r.DELETE("/video/:username/:videoid", handleDeleteVideo)
+	r.GET("/proxies", handleProxyStatus)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	if dir := tts.LocalStoreDir(); dir != "" {
+		r.Static("/tiktok", dir)
+	}
 
 	if err := http.ListenAndServe("0.0.0.0:8082", r); err != nil {
 		log.WithError(err).Fatal("could not listen and serve")
@@ -0,0 +1,96 @@
+package tiktokscraper
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalFSStore is a BlobStore backed by a directory on local disk, so
+// tiktok-scraper can run standalone without any cloud storage provider.
+// Files it writes are served back out by the /tiktok/*key route registered
+// in cmd/main.go when TikTokScraperConfig.StorageBackend is "local".
+type LocalFSStore struct {
+	dir     string
+	baseURL string
+}
+
+func newLocalFSStore(c *TikTokScraperConfig) (*LocalFSStore, error) {
+	if err := os.MkdirAll(c.LocalStoragePath, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalFSStore{
+		dir:     c.LocalStoragePath,
+		baseURL: c.URL,
+	}, nil
+}
+
+// path resolves key to a path under l.dir, rejecting any key whose cleaned
+// path would escape l.dir (e.g. via ".." segments). This is the one place
+// containment actually needs to be guaranteed, since keys are ultimately
+// built from request-controlled input like usernames and video IDs.
+func (l *LocalFSStore) path(key string) (string, error) {
+	p := filepath.Join(l.dir, filepath.FromSlash(key))
+
+	rel, err := filepath.Rel(l.dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("tiktokscraper: key escapes storage directory: " + key)
+	}
+
+	return p, nil
+}
+
+func (l *LocalFSStore) Has(key string) bool {
+	p, err := l.path(key)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+func (l *LocalFSStore) Get(key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (l *LocalFSStore) Put(key string, body io.Reader, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	bs, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p, bs, 0o644)
+}
+
+func (l *LocalFSStore) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (l *LocalFSStore) PublicURL(key string) string {
+	return l.baseURL + "/tiktok/" + key
+}
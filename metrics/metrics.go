@@ -0,0 +1,55 @@
+// Package metrics holds the Prometheus collectors tiktok-scraper reports
+// its operational health through. cmd/main.go exposes them on /metrics via
+// promhttp; the scraper package updates them as it works.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ScrapeTotal counts ScrapeVideo calls by result: "success",
+	// "cache_hit", "not_found", "invalid", or "error".
+	ScrapeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tiktok_scrape_total",
+		Help: "Total number of ScrapeVideo calls, by result.",
+	}, []string{"result"})
+
+	// ScrapeDuration times ScrapeVideo calls end to end, including cache
+	// hits.
+	ScrapeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tiktok_scrape_duration_seconds",
+		Help:    "Duration of ScrapeVideo calls, in seconds.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// CacheHitsTotal counts cache hits by kind: "metadata", "negative", or
+	// "video".
+	CacheHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tiktok_cache_hits_total",
+		Help: "Total number of cache hits, by kind.",
+	}, []string{"kind"})
+
+	// S3UploadBytesTotal counts bytes uploaded by the S3 blob store. Writes
+	// made by the local filesystem store are not counted.
+	S3UploadBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "s3_upload_bytes_total",
+		Help: "Total number of bytes uploaded to S3 or S3-compatible storage.",
+	})
+
+	// UpstreamStatus counts node/share/video responses by the top-level
+	// statusCode in TikTok's response body.
+	UpstreamStatus = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tiktok_upstream_status",
+		Help: "Total number of TikTok upstream API responses, by status code.",
+	}, []string{"code"})
+
+	// HTTPRequestDuration times inbound HTTP requests, by route, method,
+	// and response status.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of inbound HTTP requests, by route, method, and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
@@ -0,0 +1,13 @@
+// Package resources bundles small JS payloads that get evaluated in-page by
+// the chromedp fallback scraper, the same way pikami/tiktok-dl ships its
+// scraper.min.js bundle alongside the binary.
+package resources
+
+// UniversalDataScript pulls the raw JSON TikTok embeds for client-side
+// hydration out of a rendered video/photo page. It returns the JSON as a
+// string, or "" if the page never rendered the hydration data (e.g. a
+// captcha wall).
+const UniversalDataScript = `(() => {
+	const el = document.getElementById('__UNIVERSAL_DATA_FOR_REHYDRATION__');
+	return el ? el.textContent : '';
+})()`
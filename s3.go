@@ -1,13 +1,7 @@
 package tiktokscraper
 
 import (
-	"bytes"
-	"crypto/md5"
-	"encoding/hex"
-	"encoding/json"
 	"io"
-	"io/ioutil"
-	"net/http"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -17,33 +11,67 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/threadproc/tiktok-scraper/metrics"
 )
 
-func (tts *TikTokScraper) initS3() error {
-	var err error
-	tts.awsSession, err = session.NewSession(&aws.Config{
-		Region: &tts.c.AWSRegion,
+// countingReader wraps a reader to track how many bytes flow through it, so
+// Put can report s3_upload_bytes_total without buffering the whole upload.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// S3BlobStore is the default BlobStore, backed by AWS S3 or, when
+// c.S3EndpointURL is set, any S3-compatible endpoint (MinIO, Backblaze B2,
+// Cloudflare R2, ...).
+type S3BlobStore struct {
+	bucket  string
+	baseURL string
+
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+	svc        *s3.S3
+}
+
+func newS3BlobStore(c *TikTokScraperConfig) (*S3BlobStore, error) {
+	cfg := &aws.Config{
+		Region: &c.AWSRegion,
 		Credentials: credentials.NewStaticCredentials(
-			tts.c.AWSAccessKeyID,
-			tts.c.AWSSecretKey,
+			c.AWSAccessKeyID,
+			c.AWSSecretKey,
 			"",
 		),
-	})
-	if err != nil {
-		return err
+	}
+	if c.S3EndpointURL != "" {
+		cfg.Endpoint = aws.String(c.S3EndpointURL)
+		cfg.S3ForcePathStyle = aws.Bool(true)
 	}
 
-	tts.s3Uploader = s3manager.NewUploader(tts.awsSession)
-	tts.s3Downloader = s3manager.NewDownloader(tts.awsSession)
-	tts.s3 = s3.New(tts.awsSession)
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return &S3BlobStore{
+		bucket:     c.BucketName,
+		baseURL:    c.URL,
+		uploader:   s3manager.NewUploader(sess),
+		downloader: s3manager.NewDownloader(sess),
+		svc:        s3.New(sess),
+	}, nil
 }
 
-func (tts *TikTokScraper) cached(key string) bool {
-	_, err := tts.s3.HeadObject(&s3.HeadObjectInput{
-		Bucket: &tts.c.BucketName,
-		Key:    aws.String(key),
+func (s *S3BlobStore) Has(key string) bool {
+	_, err := s.svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    aws.String("tiktok/" + key),
 	})
 
 	if err == nil {
@@ -59,138 +87,44 @@ func (tts *TikTokScraper) cached(key string) bool {
 	if aerr.Code() == "NotFound" {
 		return false
 	}
-	if err != nil {
-		log.WithError(err).Error("failed to HEAD object")
-		return false
-	}
 
-	return true
+	log.WithError(err).Error("failed to HEAD object")
+	return false
 }
 
-func (tts *TikTokScraper) cachedMetadata(key string) (*TikTokMeta, error) {
-	isCached := tts.cached("tiktok/" + key + ".json")
-	if !isCached {
-		return nil, nil
-	}
-
-	// get it from the cache
-	obj, err := tts.s3.GetObject(&s3.GetObjectInput{
-		Bucket: &tts.c.BucketName,
-		Key:    aws.String("tiktok/" + key + ".json"),
+func (s *S3BlobStore) Get(key string) (io.ReadCloser, error) {
+	obj, err := s.svc.GetObject(&s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    aws.String("tiktok/" + key),
 	})
 	if err != nil {
 		return nil, err
 	}
-	defer obj.Body.Close()
-
-	ttm := &TikTokMeta{}
-	bs, err := ioutil.ReadAll(obj.Body)
-	if err != nil {
-		return nil, err
-	}
-	if err = json.Unmarshal(bs, ttm); err != nil {
-		return nil, err
-	}
 
-	return ttm, nil
+	return obj.Body, nil
 }
 
-func (tts *TikTokScraper) cacheMetadata(key string, ttm *TikTokMeta) error {
-	jsbs, err := json.Marshal(ttm)
-	if err != nil {
-		return err
-	}
-	rd := bytes.NewReader(jsbs)
-
-	_, err = tts.s3Uploader.Upload(&s3manager.UploadInput{
-		Bucket:      &tts.c.BucketName,
-		Key:         aws.String("tiktok/" + key + ".json"),
-		Body:        rd,
-		ContentType: aws.String("application/json"),
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+func (s *S3BlobStore) Put(key string, body io.Reader, contentType string) error {
+	counted := &countingReader{r: body}
 
-func (tts *TikTokScraper) cacheVideo(key string, body io.ReadCloser, ctype string) error {
-	_, err := tts.s3Uploader.Upload(&s3manager.UploadInput{
-		Bucket:      &tts.c.BucketName,
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      &s.bucket,
 		Key:         aws.String("tiktok/" + key),
-		Body:        body,
-		ContentType: aws.String(ctype),
+		Body:        counted,
+		ContentType: aws.String(contentType),
 	})
+	metrics.S3UploadBytesTotal.Add(float64(counted.n))
 	return err
 }
 
-func (tts *TikTokScraper) processImages(ttm *TikTokMeta) error {
-	cover, err := tts.cacheImage(ttm.Video.Cover)
-	if err != nil {
-		return err
-	}
-	ttm.Video.Cover = cover
-
-	originCover, err := tts.cacheImage(ttm.Video.OriginCover)
-	if err != nil {
-		return err
-	}
-	ttm.Video.OriginCover = originCover
-
-	dynamicCover, err := tts.cacheImage(ttm.Video.DynamicCover)
-	if err != nil {
-		return err
-	}
-	ttm.Video.DynamicCover = dynamicCover
-
-	avatarLarge, err := tts.cacheImage(ttm.Author.AvatarLarger)
-	if err != nil {
-		return err
-	}
-	ttm.Author.AvatarLarger = avatarLarge
-
-	avatarMedium, err := tts.cacheImage(ttm.Author.AvatarMedium)
-	if err != nil {
-		return err
-	}
-	ttm.Author.AvatarMedium = avatarMedium
-
-	avatarThumb, err := tts.cacheImage(ttm.Author.AvatarThumb)
-	if err != nil {
-		return err
-	}
-	ttm.Author.AvatarThumb = avatarThumb
-
-	return nil
-}
-
-func (tts *TikTokScraper) cacheImage(url string) (string, error) {
-	sum := md5.Sum([]byte(url))
-	hash := hex.EncodeToString(sum[:])
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-	for k, v := range tts.cookies {
-		req.AddCookie(&http.Cookie{Name: k, Value: v})
-	}
-
-	resp, err := tts.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	_, err = tts.s3Uploader.Upload(&s3manager.UploadInput{
-		Bucket:      &tts.c.BucketName,
-		Key:         aws.String("tiktok/img/" + hash),
-		Body:        resp.Body,
-		ContentType: aws.String(resp.Header.Get("Content-Type")),
+func (s *S3BlobStore) Delete(key string) error {
+	_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    aws.String("tiktok/" + key),
 	})
-	if err != nil {
-		return "", err
-	}
+	return err
+}
 
-	return tts.c.URL + "/tiktok/img/" + hash, nil
+func (s *S3BlobStore) PublicURL(key string) string {
+	return s.baseURL + "/tiktok/" + key
 }